@@ -0,0 +1,253 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/config"
+	"github.com/joyent/triton-service-groups/keys"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newKeysCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage tsg_keys rows",
+	}
+
+	cmd.AddCommand(newKeysListCommand())
+	cmd.AddCommand(newKeysAddCommand())
+	cmd.AddCommand(newKeysArchiveCommand())
+	cmd.AddCommand(newKeysRotateCommand())
+	cmd.AddCommand(newKeysMigrateMaterialCommand())
+
+	return cmd
+}
+
+func newKeysListCommand() *cobra.Command {
+	var accountID, output string
+	var pageSize int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List keys for an account",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := newStore()
+			if err != nil {
+				return err
+			}
+
+			return listKeys(cmd.Context(), store, accountID, pageSize, output)
+		},
+	}
+
+	cmd.Flags().StringVar(&accountID, "account-id", "", "account id to list keys for")
+	cmd.Flags().StringVar(&output, "output", "table", "output format: json or table")
+	cmd.Flags().IntVar(&pageSize, "page-size", 100, "number of keys to fetch per page")
+	cmd.MarkFlagRequired("account-id") // nolint: errcheck
+
+	return cmd
+}
+
+func newKeysAddCommand() *cobra.Command {
+	var accountID, name, fingerprint, material string
+
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "Add a key for an account",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := newStore()
+			if err != nil {
+				return err
+			}
+
+			k := keys.New(store)
+			k.AccountID = accountID
+			k.Name = name
+			k.Fingerprint = fingerprint
+			k.Material = material
+
+			if err := k.Insert(cmd.Context()); err != nil {
+				return errors.Wrap(err, "failed to add key")
+			}
+
+			fmt.Fprintln(os.Stdout, k.ID)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&accountID, "account-id", "", "account id the key belongs to")
+	cmd.Flags().StringVar(&name, "name", "", "key name")
+	cmd.Flags().StringVar(&fingerprint, "fingerprint", "", "key fingerprint")
+	cmd.Flags().StringVar(&material, "material", "", "key material")
+	cmd.MarkFlagRequired("account-id")  // nolint: errcheck
+	cmd.MarkFlagRequired("name")        // nolint: errcheck
+	cmd.MarkFlagRequired("fingerprint") // nolint: errcheck
+	cmd.MarkFlagRequired("material")    // nolint: errcheck
+
+	return cmd
+}
+
+func newKeysArchiveCommand() *cobra.Command {
+	var id string
+
+	cmd := &cobra.Command{
+		Use:   "archive",
+		Short: "Archive a key by id",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := newStore()
+			if err != nil {
+				return err
+			}
+
+			k, err := store.FindByID(cmd.Context(), id)
+			if err != nil {
+				return errors.Wrap(err, "failed to find key")
+			}
+
+			return errors.Wrap(k.Archive(cmd.Context()), "failed to archive key")
+		},
+	}
+
+	cmd.Flags().StringVar(&id, "id", "", "id of the key to archive")
+	cmd.MarkFlagRequired("id") // nolint: errcheck
+
+	return cmd
+}
+
+func newKeysRotateCommand() *cobra.Command {
+	var id string
+
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Re-wrap a key's data key under the current master key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := newStore()
+			if err != nil {
+				return err
+			}
+
+			return errors.Wrap(store.RotateKey(cmd.Context(), id), "failed to rotate key")
+		},
+	}
+
+	cmd.Flags().StringVar(&id, "id", "", "id of the key to rotate")
+	cmd.MarkFlagRequired("id") // nolint: errcheck
+
+	return cmd
+}
+
+func newKeysMigrateMaterialCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate-material",
+		Short: "Encrypt any tsg_keys rows still holding plaintext material",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := newStore()
+			if err != nil {
+				return err
+			}
+
+			migrated, err := keys.MigratePlaintextMaterial(cmd.Context(), store)
+			if err != nil {
+				return errors.Wrap(err, "failed to migrate key material")
+			}
+
+			fmt.Fprintf(os.Stdout, "encrypted %d key(s)\n", migrated)
+
+			return nil
+		},
+	}
+}
+
+func listKeys(ctx context.Context, store *keys.Store, accountID string, pageSize int, output string) error {
+	var offset int
+	for {
+		page, err := store.List(ctx, accountID, pageSize, offset)
+		if err != nil {
+			return errors.Wrap(err, "failed to list keys")
+		}
+		if len(page) == 0 {
+			return nil
+		}
+
+		if err := printKeys(page, output); err != nil {
+			return err
+		}
+
+		offset += len(page)
+		if len(page) < pageSize {
+			return nil
+		}
+	}
+}
+
+// redactedKey mirrors keys.Key without Material, which Store.List and
+// Store.FindByID return decrypted. printKeys' json output must never dump
+// plaintext key material to stdout the way its table output already
+// doesn't.
+type redactedKey struct {
+	ID          string
+	Name        string
+	Fingerprint string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	AccountID   string
+	Archived    bool
+}
+
+func printKeys(page []*keys.Key, output string) error {
+	switch output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		for _, k := range page {
+			redacted := redactedKey{
+				ID:          k.ID,
+				Name:        k.Name,
+				Fingerprint: k.Fingerprint,
+				CreatedAt:   k.CreatedAt,
+				UpdatedAt:   k.UpdatedAt,
+				AccountID:   k.AccountID,
+				Archived:    k.Archived,
+			}
+
+			if err := enc.Encode(redacted); err != nil {
+				return errors.Wrap(err, "failed to encode key")
+			}
+		}
+
+		return nil
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, k := range page {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", k.ID, k.Name, k.Fingerprint, k.Archived)
+		}
+
+		return w.Flush()
+	default:
+		return errors.Errorf("unsupported output format %q", output)
+	}
+}
+
+// newStore loads the default config and connects a keys.Store to it, so
+// every subcommand shares the same connection and encryption setup the
+// HTTP server uses.
+func newStore() (*keys.Store, error) {
+	cfg, err := config.NewDefault()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load config")
+	}
+
+	pool, err := pgx.NewConnPool(pgx.ConnPoolConfig(cfg.DBPool))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to database")
+	}
+
+	return keys.NewStore(pool), nil
+}
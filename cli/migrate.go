@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand() *cobra.Command {
+	var dir string
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending SQL schema migrations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.NewDefault()
+			if err != nil {
+				return errors.Wrap(err, "failed to load config")
+			}
+
+			pool, err := pgx.NewConnPool(pgx.ConnPoolConfig(cfg.DBPool))
+			if err != nil {
+				return errors.Wrap(err, "failed to connect to database")
+			}
+
+			applied, err := applyMigrations(cmd.Context(), pool, dir)
+			if err != nil {
+				return errors.Wrap(err, "failed to apply migrations")
+			}
+
+			fmt.Fprintf(os.Stdout, "applied %d migration(s)\n", applied)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "migrations", "directory containing *.up.sql migration files")
+
+	return cmd
+}
+
+// schemaMigrationsTable tracks which migration versions have already been
+// applied, so applyMigrations is safe to run repeatedly.
+const schemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+`
+
+// applyMigrations runs every *.up.sql file under dir not already recorded
+// in schema_migrations, in ascending version order, each inside its own
+// transaction.
+func applyMigrations(ctx context.Context, pool *pgx.ConnPool, dir string) (int, error) {
+	if _, err := pool.ExecEx(ctx, schemaMigrationsTable, nil); err != nil {
+		return 0, errors.Wrap(err, "failed to create schema_migrations table")
+	}
+
+	pending, err := pendingMigrations(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var applied int
+	for _, m := range pending {
+		ok, err := applyMigration(ctx, pool, m)
+		if err != nil {
+			return applied, err
+		}
+		if ok {
+			applied++
+		}
+	}
+
+	return applied, nil
+}
+
+// applyMigration applies a single migration if its version isn't already
+// recorded in schema_migrations, reporting whether it ran.
+func applyMigration(ctx context.Context, pool *pgx.ConnPool, m migrationFile) (bool, error) {
+	var exists bool
+	err := pool.QueryRowEx(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1);`, nil, m.version).Scan(&exists)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to check migration %d", m.version)
+	}
+	if exists {
+		return false, nil
+	}
+
+	sql, err := os.ReadFile(m.path)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read migration %d", m.version)
+	}
+
+	tx, err := pool.BeginEx(ctx, nil)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to begin transaction for migration %d", m.version)
+	}
+
+	if _, err := tx.ExecEx(ctx, string(sql), nil); err != nil {
+		tx.Rollback() // nolint: errcheck
+		return false, errors.Wrapf(err, "failed to apply migration %d", m.version)
+	}
+
+	if _, err := tx.ExecEx(ctx, `INSERT INTO schema_migrations (version) VALUES ($1);`, nil, m.version); err != nil {
+		tx.Rollback() // nolint: errcheck
+		return false, errors.Wrapf(err, "failed to record migration %d", m.version)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.Wrapf(err, "failed to commit migration %d", m.version)
+	}
+
+	return true, nil
+}
+
+// migrationFile is a single *.up.sql file under migrations/, named by the
+// golang-migrate convention (e.g. 000002_encrypt_key_material.up.sql).
+type migrationFile struct {
+	version int64
+	path    string
+}
+
+// pendingMigrations lists every *.up.sql file under dir, ordered by the
+// numeric version prefix in its filename.
+func pendingMigrations(dir string) ([]migrationFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read migrations directory %q", dir)
+	}
+
+	var files []migrationFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".up.sql") {
+			continue
+		}
+
+		version, err := strconv.ParseInt(strings.SplitN(entry.Name(), "_", 2)[0], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse migration version from %q", entry.Name())
+		}
+
+		files = append(files, migrationFile{version: version, path: filepath.Join(dir, entry.Name())})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].version < files[j].version })
+
+	return files, nil
+}
@@ -0,0 +1,56 @@
+// Package cli assembles the tsg Cobra command tree, so operators can
+// manage tsg_keys rows and run the server from the same binary and the
+// same keys.Store code path the HTTP server uses.
+package cli
+
+import (
+	"github.com/joyent/triton-service-groups/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewRootCommand builds the tsg command tree.
+func NewRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "tsg",
+		Short: "Run the tsg server and manage tsg_keys rows",
+	}
+
+	bindPersistentFlags(root)
+
+	root.AddCommand(newServerCommand())
+	root.AddCommand(newKeysCommand())
+	root.AddCommand(newMigrateCommand())
+
+	return root
+}
+
+// bindPersistentFlags registers flags for the existing viper keys that
+// config.NewDefault reads, so env vars, a config file, and flags all
+// resolve to the same configuration regardless of how the operator sets
+// them.
+func bindPersistentFlags(root *cobra.Command) {
+	flags := root.PersistentFlags()
+
+	flags.String("pg-host", "", "database host")
+	flags.Int("pg-port", 0, "database port")
+	flags.String("pg-database", "", "database name")
+	flags.String("pg-user", "", "database user")
+	flags.String("pg-password", "", "database password")
+	flags.String("log-level", "INFO", "log level (FATAL, ERROR, WARN, INFO, DEBUG)")
+	flags.String("agent-log-format", "", "agent log format")
+	flags.String("agent-log-backend", "", "agent log backend (zerolog-console, zerolog-json, slog)")
+	flags.String("http-bind", "", "HTTP server bind address")
+	flags.Int("http-port", 0, "HTTP server port")
+
+	viper.BindPFlag(config.KeyPGHost, flags.Lookup("pg-host"))                    // nolint: errcheck
+	viper.BindPFlag(config.KeyPGPort, flags.Lookup("pg-port"))                    // nolint: errcheck
+	viper.BindPFlag(config.KeyPGDatabase, flags.Lookup("pg-database"))            // nolint: errcheck
+	viper.BindPFlag(config.KeyPGUser, flags.Lookup("pg-user"))                    // nolint: errcheck
+	viper.BindPFlag(config.KeyPGPassword, flags.Lookup("pg-password"))            // nolint: errcheck
+	viper.BindPFlag(config.KeyLogLevel, flags.Lookup("log-level"))                // nolint: errcheck
+	viper.BindPFlag(config.KeyAgentLogFormat, flags.Lookup("agent-log-format"))   // nolint: errcheck
+	viper.BindPFlag(config.KeyAgentLogBackend, flags.Lookup("agent-log-backend")) // nolint: errcheck
+	viper.BindPFlag(config.KeyHTTPServerBind, flags.Lookup("http-bind"))          // nolint: errcheck
+	viper.BindPFlag(config.KeyHTTPServerPort, flags.Lookup("http-port"))          // nolint: errcheck
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/config"
+	"github.com/joyent/triton-service-groups/log"
+	"github.com/joyent/triton-service-groups/metrics"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newServerCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "server",
+		Short: "Run the tsg server (serves only the optional metrics listener)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.NewDefault()
+			if err != nil {
+				return errors.Wrap(err, "failed to load config")
+			}
+
+			return runServer(cmd.Context(), cfg)
+		},
+	}
+}
+
+// runServer starts the Prometheus /metrics listener when Metrics.Enabled
+// and blocks until ctx is canceled or the process receives SIGINT/SIGTERM.
+// It serves no public HTTP handlers: nothing listens on the HTTPServer
+// bind and port.
+func runServer(ctx context.Context, cfg *config.Config) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger := log.New(cfg.Agent.LogBackend, "server")
+	logger.Warn("tsg server: HTTP handlers are not yet implemented")
+
+	if cfg.Metrics.Enabled {
+		pool, err := pgx.NewConnPool(pgx.ConnPoolConfig(cfg.DBPool))
+		if err != nil {
+			return errors.Wrap(err, "failed to connect to database")
+		}
+		defer pool.Close()
+
+		metrics.PollPoolStats(ctx, pool, 15*time.Second)
+
+		metricsLogger := log.New(cfg.Agent.LogBackend, "metrics")
+		go func() {
+			if err := metrics.ListenAndServe(ctx, cfg.Metrics.Bind, cfg.Metrics.Port, cfg.Metrics.PprofEnabled); err != nil {
+				metricsLogger.Error("metrics listener exited", log.F("error", err.Error()))
+			}
+		}()
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
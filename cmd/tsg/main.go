@@ -0,0 +1,16 @@
+// Command tsg runs the tsg server and manages tsg_keys rows.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/joyent/triton-service-groups/cli"
+)
+
+func main() {
+	if err := cli.NewRootCommand().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
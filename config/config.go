@@ -1,14 +1,18 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"strings"
 
 	"github.com/jackc/pgx"
 	"github.com/joyent/triton-service-groups/buildtime"
+	"github.com/joyent/triton-service-groups/keys"
+	"github.com/joyent/triton-service-groups/log"
+	"github.com/joyent/triton-service-groups/metrics"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	zerologlog "github.com/rs/zerolog/log"
 	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 )
@@ -19,10 +23,40 @@ type Config struct {
 	DBPool
 	Agent
 	HTTPServer
+	Encryption
+	Audit
+	Metrics
 }
 
 type Agent struct {
-	LogFormat LogFormat
+	LogFormat  LogFormat
+	LogBackend log.Backend
+}
+
+// Audit holds the settings used to build the keys.AuditLogger that
+// records key mutations.
+type Audit struct {
+	// Backend selects which AuditSink implementation is installed:
+	// "zerolog", "file", "syslog", or "webhook". An empty Backend leaves
+	// auditing disabled.
+	Backend string
+
+	// BufferSize bounds how many pending events the AuditLogger holds
+	// before dropping events rather than blocking a caller.
+	BufferSize int
+}
+
+// Encryption holds the settings used to build the keys.KMSProvider that
+// envelope-encrypts Key.Material at rest.
+type Encryption struct {
+	// Backend selects which KMSProvider implementation is installed:
+	// "local", "vault", "awskms", or "gcpkms". An empty Backend leaves
+	// encryption disabled.
+	Backend string
+
+	// KeyVersion identifies the active master key, so wrapped data keys
+	// can be traced back to the key that produced them across rotations.
+	KeyVersion string
 }
 
 type HTTPServer struct {
@@ -31,32 +65,47 @@ type HTTPServer struct {
 	Logger zerolog.Logger
 }
 
+// Metrics holds the settings for the Prometheus /metrics handler and the
+// optional /debug/pprof endpoint, both served on their own bind/port so
+// they aren't reachable through the public HTTPServer listener.
+type Metrics struct {
+	// Enabled turns on the /metrics handler.
+	Enabled bool
+	Bind    string
+	Port    uint16
+
+	// PprofEnabled turns on /debug/pprof alongside /metrics.
+	PprofEnabled bool
+}
+
 type PGXLogger struct {
-	logger zerolog.Logger
+	logger log.Logger
 }
 
 // Custom logging facade that implements the pgx.Logger interface in order to
-// log through Zerolog
+// log through the log package, so the backend selected via
+// KeyAgentLogBackend applies to pgx output as well.
 func (l *PGXLogger) Log(level pgx.LogLevel, msg string, data map[string]interface{}) {
-	var zlevel zerolog.Level
+	if level == pgx.LogLevelNone {
+		return
+	}
+
+	fields := make([]log.Field, 0, len(data))
+	for k, v := range data {
+		fields = append(fields, log.F(k, v))
+	}
+
 	switch level {
-	case pgx.LogLevelNone:
-		zlevel = zerolog.NoLevel
 	case pgx.LogLevelError:
-		zlevel = zerolog.ErrorLevel
+		l.logger.Error(msg, fields...)
 	case pgx.LogLevelWarn:
-		zlevel = zerolog.WarnLevel
-	case pgx.LogLevelInfo:
+		l.logger.Warn(msg, fields...)
+	case pgx.LogLevelInfo, pgx.LogLevelDebug:
 		// NOTE(justinwr): We want to force into debug output through zerolog.
-		zlevel = zerolog.DebugLevel
-	case pgx.LogLevelDebug:
-		zlevel = zerolog.DebugLevel
+		l.logger.Debug(msg, fields...)
 	default:
-		zlevel = zerolog.DebugLevel
+		l.logger.Debug(msg, fields...)
 	}
-
-	pgxlog := l.logger.With().Fields(data).Logger()
-	pgxlog.WithLevel(zlevel).Msg(msg)
 }
 
 func NewDefault() (cfg *Config, err error) {
@@ -84,11 +133,16 @@ func NewDefault() (cfg *Config, err error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "unable to parse the log format")
 		}
+
+		agentConfig.LogBackend, err = log.ParseBackend(viper.GetString(KeyAgentLogBackend))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse the log backend")
+		}
 	}
 
 	httpServerConfig := HTTPServer{}
 	{
-		httpServerConfig.Logger = log.Logger.With().Str("module", "http").Logger()
+		httpServerConfig.Logger = zerologlog.Logger.With().Str("module", "http").Logger()
 
 		httpServerConfig.Bind = "127.0.0.1"
 		if bind := viper.GetString(KeyHTTPServerBind); bind != "" {
@@ -103,16 +157,60 @@ func NewDefault() (cfg *Config, err error) {
 
 	pgxLogger := &PGXLogger{}
 	{
-		pgxLogger.logger = log.Logger.With().Str("module", "pgx").Logger()
+		pgxLogger.logger = log.New(agentConfig.LogBackend, "pgx")
+	}
+
+	keys.SetLogger(log.New(agentConfig.LogBackend, "keys"))
+
+	encryptionConfig := Encryption{
+		Backend:    viper.GetString(KeyEncryptionBackend),
+		KeyVersion: viper.GetString(KeyEncryptionKeyVersion),
+	}
+	if provider, err := newKMSProvider(encryptionConfig); err != nil {
+		return nil, errors.Wrap(err, "unable to configure key material encryption")
+	} else if provider != nil {
+		keys.SetKMSProvider(provider)
+	}
+
+	auditConfig := Audit{
+		Backend:    viper.GetString(KeyAuditBackend),
+		BufferSize: viper.GetInt(KeyAuditBufferSize),
+	}
+	if auditConfig.BufferSize == 0 {
+		auditConfig.BufferSize = 1000
+	}
+	if sink, err := newAuditSink(auditConfig, httpServerConfig.Logger); err != nil {
+		return nil, errors.Wrap(err, "unable to configure key audit logging")
+	} else if sink != nil {
+		secret := []byte(viper.GetString(KeyAuditHMACSecret))
+		keys.SetAuditLogger(keys.NewAuditLogger(sink, secret, auditConfig.BufferSize))
+	}
+
+	metricsConfig := Metrics{
+		Enabled:      viper.GetBool(KeyMetricsEnabled),
+		Bind:         viper.GetString(KeyMetricsBind),
+		Port:         uint16(3001),
+		PprofEnabled: viper.GetBool(KeyMetricsPprofEnabled),
+	}
+	if metricsConfig.Bind == "" {
+		metricsConfig.Bind = "127.0.0.1"
+	}
+	if port := viper.GetInt(KeyMetricsPort); port != 0 {
+		metricsConfig.Port = uint16(port)
 	}
 
 	// default to commonly configured CockroachDB port
 	viper.SetDefault(KeyPGPort, uint16(26257))
 
+	maxConnections := 5
+	if n := viper.GetInt(KeyPGMaxConnections); n != 0 {
+		maxConnections = n
+	}
+
 	return &Config{
 		DBPool: pgx.ConnPoolConfig{
-			MaxConnections: 5,
-			AfterConnect:   nil,
+			MaxConnections: maxConnections,
+			AfterConnect:   afterConnect,
 			AcquireTimeout: 0,
 
 			ConnConfig: pgx.ConnConfig{
@@ -131,9 +229,100 @@ func NewDefault() (cfg *Config, err error) {
 		},
 		Agent:      agentConfig,
 		HTTPServer: httpServerConfig,
+		Encryption: encryptionConfig,
+		Audit:      auditConfig,
+		Metrics:    metricsConfig,
 	}, nil
 }
 
+// afterConnect runs once per new pgx pool connection: it registers the
+// keys package's prepared statements and records the connection for the
+// tsg_pgx_pool_connections_opened_total metric.
+func afterConnect(conn *pgx.Conn) error {
+	metrics.PoolConnectionsOpened.Inc()
+
+	return keys.PrepareStatements(conn)
+}
+
+// newAuditSink builds the keys.AuditSink selected by cfg.Backend. A nil
+// sink and nil error are returned when Backend is unset, leaving key
+// mutation auditing disabled.
+func newAuditSink(cfg Audit, logger zerolog.Logger) (keys.AuditSink, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "":
+		return nil, nil
+	case "zerolog":
+		return &keys.ZerologAuditSink{Logger: logger.With().Str("module", "audit").Logger()}, nil
+	case "file":
+		return keys.NewFileAuditSink(viper.GetString(KeyAuditFilePath))
+	case "syslog":
+		return keys.NewSyslogAuditSink(buildtime.PROGNAME)
+	case "webhook":
+		return keys.NewWebhookAuditSink(viper.GetString(KeyAuditWebhookURL)), nil
+	default:
+		return nil, errors.Errorf("unsupported audit backend %q", cfg.Backend)
+	}
+}
+
+// newKMSProvider builds the keys.KMSProvider selected by cfg.Backend. A
+// nil provider and nil error are returned when Backend is unset, leaving
+// key material encryption disabled.
+func newKMSProvider(cfg Encryption) (keys.KMSProvider, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "":
+		return nil, nil
+	case "local":
+		masterKey, err := base64.StdEncoding.DecodeString(viper.GetString(KeyEncryptionMasterKey))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to decode master key")
+		}
+
+		previous, err := decodePreviousMasterKeys(viper.GetStringMapString(KeyEncryptionPreviousKeys))
+		if err != nil {
+			return nil, err
+		}
+
+		return keys.NewLocalAESProvider(cfg.KeyVersion, masterKey, previous)
+	case "vault":
+		return &keys.VaultKMSProvider{
+			Address: viper.GetString(KeyEncryptionVaultAddress),
+			Token:   viper.GetString(KeyEncryptionVaultToken),
+			KeyName: viper.GetString(KeyEncryptionKeyVersion),
+		}, nil
+	case "awskms":
+		return &keys.AWSKMSProvider{
+			KeyID:  viper.GetString(KeyEncryptionKeyVersion),
+			Region: viper.GetString(KeyEncryptionAWSRegion),
+		}, nil
+	case "gcpkms":
+		return &keys.GCPKMSProvider{
+			KeyName: viper.GetString(KeyEncryptionKeyVersion),
+		}, nil
+	default:
+		return nil, errors.Errorf("unsupported encryption backend %q", cfg.Backend)
+	}
+}
+
+// decodePreviousMasterKeys base64-decodes each retired master key in raw,
+// keyed by the KeyEncryptionKeyVersion value it was active under.
+func decodePreviousMasterKeys(raw map[string]string) (map[string][]byte, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	previous := make(map[string][]byte, len(raw))
+	for version, encoded := range raw {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to decode previous master key %q", version)
+		}
+
+		previous[version] = key
+	}
+
+	return previous, nil
+}
+
 // IsDebug returns true when the server is configured for debug level
 func IsDebug() bool {
 	switch logLevel := strings.ToUpper(viper.GetString(KeyLogLevel)); logLevel {
@@ -142,4 +331,4 @@ func IsDebug() bool {
 	default:
 		return false
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,79 @@
+package config
+
+// Viper keys controlling Key.Material envelope encryption. See
+// Encryption and newKMSProvider.
+const (
+	// KeyEncryptionBackend selects the KMSProvider implementation:
+	// "local", "vault", "awskms", or "gcpkms". Left unset, encryption is
+	// disabled.
+	KeyEncryptionBackend = "encryption.backend"
+
+	// KeyEncryptionKeyVersion identifies the active master key across all
+	// backends, whether that's a local key version, a Vault transit key
+	// name, an AWS KMS key ID, or a GCP KMS resource name.
+	KeyEncryptionKeyVersion = "encryption.key_version"
+
+	// KeyEncryptionMasterKey holds a base64-encoded 32-byte AES-256 key,
+	// used only by the "local" backend.
+	KeyEncryptionMasterKey = "encryption.master_key"
+
+	// KeyEncryptionPreviousKeys holds retired master keys by version,
+	// base64-encoded the same as KeyEncryptionMasterKey, used only by the
+	// "local" backend. Rows still wrapped under a retired version need
+	// its key present here until they're re-wrapped under the current one.
+	KeyEncryptionPreviousKeys = "encryption.previous_keys"
+
+	// KeyEncryptionVaultAddress and KeyEncryptionVaultToken configure the
+	// "vault" backend.
+	KeyEncryptionVaultAddress = "encryption.vault.address"
+	KeyEncryptionVaultToken   = "encryption.vault.token"
+
+	// KeyEncryptionAWSRegion configures the "awskms" backend.
+	KeyEncryptionAWSRegion = "encryption.aws.region"
+)
+
+// Viper keys controlling the audit trail for key mutations. See Audit
+// and newAuditSink.
+const (
+	// KeyAuditBackend selects the AuditSink implementation: "zerolog",
+	// "file", "syslog", or "webhook". Left unset, auditing is disabled.
+	KeyAuditBackend = "audit.backend"
+
+	// KeyAuditBufferSize bounds the AuditLogger's pending event buffer.
+	KeyAuditBufferSize = "audit.buffer_size"
+
+	// KeyAuditHMACSecret signs the audit event chain so tampering with
+	// the trail after the fact is detectable.
+	KeyAuditHMACSecret = "audit.hmac_secret"
+
+	// KeyAuditFilePath configures the "file" backend.
+	KeyAuditFilePath = "audit.file.path"
+
+	// KeyAuditWebhookURL configures the "webhook" backend.
+	KeyAuditWebhookURL = "audit.webhook.url"
+)
+
+// KeyAgentLogBackend selects the log.Backend used for Agent, PGXLogger,
+// and keys package logging: "zerolog-console" (the default),
+// "zerolog-json", or "slog".
+const KeyAgentLogBackend = "agent.log_backend"
+
+// KeyPGMaxConnections overrides the pgx pool's MaxConnections, which
+// otherwise defaults to 5.
+const KeyPGMaxConnections = "pg.max_connections"
+
+// Viper keys controlling the Prometheus /metrics and /debug/pprof
+// endpoints. See Metrics.
+const (
+	// KeyMetricsEnabled turns on the /metrics handler.
+	KeyMetricsEnabled = "metrics.enabled"
+
+	// KeyMetricsBind and KeyMetricsPort configure the dedicated listener
+	// /metrics (and /debug/pprof) are served on, separate from
+	// HTTPServer's bind/port.
+	KeyMetricsBind = "metrics.bind"
+	KeyMetricsPort = "metrics.port"
+
+	// KeyMetricsPprofEnabled turns on /debug/pprof alongside /metrics.
+	KeyMetricsPprofEnabled = "metrics.pprof_enabled"
+)
@@ -0,0 +1,164 @@
+package keys
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joyent/triton-service-groups/log"
+)
+
+// AuditAction identifies the mutation an AuditEvent describes.
+type AuditAction string
+
+const (
+	AuditActionInsert  AuditAction = "insert"
+	AuditActionSave    AuditAction = "save"
+	AuditActionArchive AuditAction = "archive"
+)
+
+// AuditEvent describes a single mutation to a tsg_keys row. HMAC chains
+// each event to the one before it through PrevHMAC, so editing an event in
+// the sink's storage invalidates that event's HMAC, and removing one
+// leaves the next event's PrevHMAC matching no stored event.
+type AuditEvent struct {
+	Timestamp   time.Time
+	RequestID   string
+	Actor       string
+	KeyID       string
+	Fingerprint string
+	Action      AuditAction
+	Before      map[string]interface{}
+	After       map[string]interface{}
+
+	PrevHMAC string
+	HMAC     string
+}
+
+// AuditSink persists AuditEvents. Implementations are expected to be
+// reasonably fast; a slow one only delays its own buffer draining, not
+// the caller that triggered the event.
+type AuditSink interface {
+	WriteAuditEvent(ctx context.Context, event AuditEvent) error
+}
+
+// AuditLogger emits AuditEvents to an AuditSink over a buffered channel
+// drained by a single goroutine. Events that arrive faster than the sink
+// can drain the buffer are dropped, and Dropped reports how many.
+type AuditLogger struct {
+	sink   AuditSink
+	secret []byte
+
+	events chan AuditEvent
+
+	mu       sync.Mutex
+	lastHMAC string
+
+	dropped uint64
+}
+
+// NewAuditLogger constructs an AuditLogger that signs events with secret
+// and buffers up to bufferSize events for sink before dropping them.
+func NewAuditLogger(sink AuditSink, secret []byte, bufferSize int) *AuditLogger {
+	l := &AuditLogger{
+		sink:   sink,
+		secret: secret,
+		events: make(chan AuditEvent, bufferSize),
+	}
+
+	go l.drain()
+
+	return l
+}
+
+// activeAuditLogger is the logger used by keys.Key to emit AuditEvents. A
+// nil logger disables auditing, so deployments that haven't configured a
+// sink keep working unchanged.
+var activeAuditLogger *AuditLogger
+
+// SetAuditLogger installs the AuditLogger used by the keys package. It is
+// typically called once during startup from config.NewDefault.
+func SetAuditLogger(l *AuditLogger) {
+	activeAuditLogger = l
+}
+
+func (l *AuditLogger) drain() {
+	for event := range l.events {
+		// Best-effort: a sink failure here isn't surfaced to the caller
+		// that triggered the mutation, since audit delivery must never
+		// block or fail a DB write.
+		_ = l.sink.WriteAuditEvent(context.Background(), event)
+	}
+}
+
+// emit signs event, chaining it to the previous event's HMAC, and hands it
+// to the buffered channel. If the buffer is full the event is dropped and
+// counted rather than blocking the caller.
+func (l *AuditLogger) emit(event AuditEvent) {
+	l.mu.Lock()
+	event.PrevHMAC = l.lastHMAC
+	event.HMAC = l.sign(event)
+	l.lastHMAC = event.HMAC
+	l.mu.Unlock()
+
+	select {
+	case l.events <- event:
+	default:
+		atomic.AddUint64(&l.dropped, 1)
+	}
+}
+
+// Dropped returns the number of events dropped because the buffer was
+// full, for exporting as a metric.
+func (l *AuditLogger) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
+// sign covers every field that records what happened and what changed, not
+// just who/why, so editing Before/After/Timestamp in the sink's stored
+// payload invalidates the chain the same as editing the identity fields.
+func (l *AuditLogger) sign(event AuditEvent) string {
+	before, _ := json.Marshal(event.Before)
+	after, _ := json.Marshal(event.After)
+
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(event.PrevHMAC))
+	mac.Write([]byte(event.RequestID))
+	mac.Write([]byte(event.Actor))
+	mac.Write([]byte(event.KeyID))
+	mac.Write([]byte(event.Fingerprint))
+	mac.Write([]byte(event.Action))
+	mac.Write([]byte(event.Timestamp.UTC().Format(time.RFC3339Nano)))
+	mac.Write(before)
+	mac.Write(after)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// auditEvent is a no-op when no AuditLogger is configured, so instrumented
+// call sites don't need to branch on whether auditing is enabled.
+func auditEvent(ctx context.Context, action AuditAction, k *Key, before map[string]interface{}) {
+	if activeAuditLogger == nil {
+		return
+	}
+
+	activeAuditLogger.emit(AuditEvent{
+		Timestamp:   time.Now(),
+		RequestID:   log.RequestIDFromContext(ctx),
+		Actor:       k.AccountID,
+		KeyID:       k.ID,
+		Fingerprint: k.Fingerprint,
+		Action:      action,
+		Before:      before,
+		After: map[string]interface{}{
+			"name":       k.Name,
+			"archived":   k.Archived,
+			"updated_at": k.UpdatedAt,
+		},
+	})
+}
@@ -0,0 +1,137 @@
+package keys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// ZerologAuditSink writes AuditEvents as structured log lines through a
+// zerolog.Logger.
+type ZerologAuditSink struct {
+	Logger zerolog.Logger
+}
+
+func (s *ZerologAuditSink) WriteAuditEvent(ctx context.Context, event AuditEvent) error {
+	s.Logger.Info().
+		Str("request_id", event.RequestID).
+		Str("actor", event.Actor).
+		Str("key_id", event.KeyID).
+		Str("fingerprint", event.Fingerprint).
+		Str("action", string(event.Action)).
+		Interface("before", event.Before).
+		Interface("after", event.After).
+		Str("hmac", event.HMAC).
+		Str("prev_hmac", event.PrevHMAC).
+		Time("timestamp", event.Timestamp).
+		Msg("key audit event")
+
+	return nil
+}
+
+// FileAuditSink appends newline-delimited JSON AuditEvents to a file.
+type FileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileAuditSink opens path for appending, creating it if necessary.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open audit log file")
+	}
+
+	return &FileAuditSink{file: f}, nil
+}
+
+func (s *FileAuditSink) WriteAuditEvent(ctx context.Context, event AuditEvent) error {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal audit event")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.file.Write(append(raw, '\n'))
+
+	return errors.Wrap(err, "failed to write audit event")
+}
+
+// SyslogAuditSink forwards AuditEvents to the local syslog daemon.
+type SyslogAuditSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogAuditSink dials the local syslog daemon under the given tag.
+func NewSyslogAuditSink(tag string) (*SyslogAuditSink, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to syslog")
+	}
+
+	return &SyslogAuditSink{writer: w}, nil
+}
+
+func (s *SyslogAuditSink) WriteAuditEvent(ctx context.Context, event AuditEvent) error {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal audit event")
+	}
+
+	return s.writer.Info(string(raw))
+}
+
+// WebhookAuditSink POSTs AuditEvents as JSON to a configured URL.
+type WebhookAuditSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// webhookTimeout bounds a single WriteAuditEvent delivery. AuditLogger.drain
+// calls WriteAuditEvent with context.Background(), so without a client
+// timeout a hung endpoint would wedge the single drain goroutine forever.
+const webhookTimeout = 10 * time.Second
+
+// NewWebhookAuditSink constructs a WebhookAuditSink posting to url.
+func NewWebhookAuditSink(url string) *WebhookAuditSink {
+	return &WebhookAuditSink{
+		URL:    url,
+		Client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (s *WebhookAuditSink) WriteAuditEvent(ctx context.Context, event AuditEvent) error {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal audit event")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(raw))
+	if err != nil {
+		return errors.Wrap(err, "failed to build audit webhook request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to deliver audit webhook")
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
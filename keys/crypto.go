@@ -0,0 +1,202 @@
+package keys
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// materialCiphertextPrefix marks a Material value as an envelope-encrypted
+// blob rather than plain text, so rows written before encryption was
+// enabled can still be read back as-is.
+const materialCiphertextPrefix = "enc:v1:"
+
+// KMSProvider wraps and unwraps the per-row data keys used to encrypt
+// Key.Material. LocalAESProvider implements it with a local master key;
+// the Vault, AWS KMS, and GCP KMS providers return ErrKMSNotConfigured
+// from Wrap and Unwrap.
+type KMSProvider interface {
+	// KeyVersion identifies the master key currently used for wrapping, so
+	// a wrapped data key can always be traced back to the key that
+	// produced it, even after the active master key has rotated.
+	KeyVersion() string
+
+	// Wrap encrypts dataKey with the provider's current master key.
+	Wrap(ctx context.Context, dataKey []byte) ([]byte, error)
+
+	// Unwrap decrypts a data key that was wrapped under keyVersion, which
+	// may not be the provider's current key version.
+	Unwrap(ctx context.Context, keyVersion string, wrappedKey []byte) ([]byte, error)
+}
+
+// activeKMS is the provider used to wrap and unwrap per-row data keys.
+var activeKMS KMSProvider
+
+// SetKMSProvider installs the KMSProvider used by the keys package to
+// encrypt and decrypt Material. It is typically called once during
+// startup from config.NewDefault.
+func SetKMSProvider(p KMSProvider) {
+	activeKMS = p
+}
+
+// envelope is the on-disk representation of an encrypted Material value.
+type envelope struct {
+	KeyVersion string `json:"key_version"`
+	WrappedKey []byte `json:"wrapped_key"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// encryptMaterial replaces k.Material with an envelope-encrypted blob
+// using a freshly generated per-row data key. It is a no-op when no
+// KMSProvider is configured or Material is empty, so plaintext rows are
+// only produced when encryption hasn't been set up.
+func encryptMaterial(ctx context.Context, k *Key) error {
+	if activeKMS == nil || k.Material == "" {
+		return nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return errors.Wrap(err, "failed to generate data key")
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize cipher")
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return errors.Wrap(err, "failed to generate nonce")
+	}
+
+	wrappedKey, err := activeKMS.Wrap(ctx, dataKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to wrap data key")
+	}
+
+	env := envelope{
+		KeyVersion: activeKMS.KeyVersion(),
+		WrappedKey: wrappedKey,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, []byte(k.Material), nil),
+	}
+
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal envelope")
+	}
+
+	k.Material = materialCiphertextPrefix + base64.StdEncoding.EncodeToString(raw)
+
+	return nil
+}
+
+// decryptMaterial replaces k.Material with its plaintext value when it is
+// an envelope-encrypted blob. A plaintext row passes through unchanged. An
+// encrypted row with no KMSProvider configured is an error rather than a
+// pass-through: returning the raw envelope as if it were plaintext would
+// hand callers ciphertext with no indication anything went wrong.
+func decryptMaterial(ctx context.Context, k *Key) error {
+	if !strings.HasPrefix(k.Material, materialCiphertextPrefix) {
+		return nil
+	}
+
+	if activeKMS == nil {
+		return errors.New("material is encrypted but no KMSProvider is configured")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(k.Material, materialCiphertextPrefix))
+	if err != nil {
+		return errors.Wrap(err, "failed to decode envelope")
+	}
+
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return errors.Wrap(err, "failed to unmarshal envelope")
+	}
+
+	dataKey, err := activeKMS.Unwrap(ctx, env.KeyVersion, env.WrappedKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to unwrap data key")
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize cipher")
+	}
+
+	plaintext, err := gcm.Open(nil, env.Nonce, env.Ciphertext, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to decrypt material")
+	}
+
+	k.Material = string(plaintext)
+
+	return nil
+}
+
+// RotateMaterialKey re-wraps the data key protecting an already-encrypted
+// Material value under the KMSProvider's current master key, without
+// decrypting or re-encrypting the ciphertext itself. It is used to rotate
+// away from a retired master key.
+func RotateMaterialKey(ctx context.Context, material string) (string, error) {
+	if activeKMS == nil {
+		return "", errors.New("no KMSProvider configured")
+	}
+
+	if !strings.HasPrefix(material, materialCiphertextPrefix) {
+		return "", errors.New("material is not encrypted")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(material, materialCiphertextPrefix))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode envelope")
+	}
+
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return "", errors.Wrap(err, "failed to unmarshal envelope")
+	}
+
+	if env.KeyVersion == activeKMS.KeyVersion() {
+		return material, nil
+	}
+
+	dataKey, err := activeKMS.Unwrap(ctx, env.KeyVersion, env.WrappedKey)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to unwrap data key")
+	}
+
+	wrappedKey, err := activeKMS.Wrap(ctx, dataKey)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to re-wrap data key")
+	}
+
+	env.KeyVersion = activeKMS.KeyVersion()
+	env.WrappedKey = wrappedKey
+
+	out, err := json.Marshal(env)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal envelope")
+	}
+
+	return materialCiphertextPrefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
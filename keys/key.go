@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx"
+	"github.com/joyent/triton-service-groups/metrics"
 	"github.com/pkg/errors"
 )
 
@@ -35,73 +36,66 @@ func New(store *Store) *Key {
 	}
 }
 
-// Insert inserts a new key into the tsg_keys table.
-func (k *Key) Insert(ctx context.Context) error {
+// Insert inserts a new key into the tsg_keys table using the prepared
+// insertKeyStmt statement, which returns the generated id and timestamps
+// directly so no follow-up FindByName round trip is needed.
+func (k *Key) Insert(ctx context.Context) (err error) {
+	defer func(start time.Time) {
+		metrics.ObserveKeyOperation("insert", k.AccountID, time.Since(start), err)
+	}(time.Now())
+
 	if k.AccountID == "" {
 		return ErrNoAccountID
 	}
 
-	query := `
-INSERT INTO tsg_keys (name, fingerprint, material, account_id, archived, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, NOW(), NOW());
-`
-
-	pool := k.store.pool
-
-	tx, err := pool.Begin()
-	if err != nil {
-		return errors.Wrap(err, "failed to begin transaction")
+	plaintext := k.Material
+	if err = encryptMaterial(ctx, k); err != nil {
+		return errors.Wrap(err, "failed to encrypt key material")
 	}
-	defer tx.Rollback() // nolint: errcheck
+	defer func() { k.Material = plaintext }()
 
-	_, err = pool.ExecEx(ctx, query, nil,
+	err = k.store.pool.QueryRowEx(ctx, insertKeyStmt, nil,
 		k.Name,
 		k.Fingerprint,
 		k.Material,
 		k.AccountID,
 		k.Archived,
-	)
+	).Scan(&k.ID, &k.CreatedAt, &k.UpdatedAt)
 	if err != nil {
+		logError(ctx, "failed to insert key", k, err)
 		return errors.Wrap(err, "failed to insert key")
 	}
 
-	if err := tx.Commit(); err != nil {
-		return errors.Wrap(err, "failed to commit transaction")
-	}
-
-	key, err := k.store.FindByName(ctx, k.Name, k.AccountID)
-	if err != nil {
-		return errors.Wrap(err, "failed to find key after insert")
-	}
-
-	k.ID = key.ID
-	k.CreatedAt = key.CreatedAt
-	k.UpdatedAt = key.UpdatedAt
+	auditEvent(ctx, AuditActionInsert, k, nil)
 
 	return nil
 }
 
-// Save saves an keys.Key object and it's field values.
-func (k *Key) Save(ctx context.Context) error {
+// Save saves an keys.Key object and it's field values using the prepared
+// updateKeyStmt statement.
+func (k *Key) Save(ctx context.Context) (err error) {
+	defer func(start time.Time) {
+		metrics.ObserveKeyOperation("save", k.AccountID, time.Since(start), err)
+	}(time.Now())
+
 	if k.ID == "" {
 		return ErrMissingID
 	}
 
-	query := `
-UPDATE tsg_keys SET (name, fingerprint, material, archived, updated_at) = ($2, $3, $4, $5, $6)
-WHERE id = $1;
-`
 	updatedAt := time.Now()
 
-	pool := k.store.pool
+	var before map[string]interface{}
+	if activeAuditLogger != nil {
+		before = k.store.findAuditSnapshot(ctx, k.ID)
+	}
 
-	tx, err := pool.Begin()
-	if err != nil {
-		return errors.Wrap(err, "failed to begin transaction")
+	plaintext := k.Material
+	if err = encryptMaterial(ctx, k); err != nil {
+		return errors.Wrap(err, "failed to encrypt key material")
 	}
-	defer tx.Rollback() // nolint: errcheck
+	defer func() { k.Material = plaintext }()
 
-	_, err = pool.ExecEx(ctx, query, nil,
+	_, err = k.store.pool.ExecEx(ctx, updateKeyStmt, nil,
 		k.ID,
 		k.Name,
 		k.Fingerprint,
@@ -110,47 +104,60 @@ WHERE id = $1;
 		updatedAt,
 	)
 	if err != nil {
+		logError(ctx, "failed to update key", k, err)
 		return errors.Wrap(err, "failed to update key")
 	}
 
-	if err := tx.Commit(); err != nil {
-		return errors.Wrap(err, "failed to commit transaction")
-	}
-
 	k.UpdatedAt = updatedAt
 
+	action := AuditActionSave
+	if k.Archived {
+		action = AuditActionArchive
+	}
+	auditEvent(ctx, action, k, before)
+
 	return nil
 }
 
+// Archive marks the key as archived and persists it, emitting an
+// AuditActionArchive event in place of the usual AuditActionSave.
+func (k *Key) Archive(ctx context.Context) error {
+	k.Archived = true
+
+	return k.Save(ctx)
+}
+
 // Exists returns a boolean and error. True if the row exists, false if it
 // doesn't, error if there was an error executing the query.
-func (k *Key) Exists(ctx context.Context) (bool, error) {
+func (k *Key) Exists(ctx context.Context) (exists bool, err error) {
+	defer func(start time.Time) {
+		metrics.ObserveKeyOperation("exists", k.AccountID, time.Since(start), err)
+	}(time.Now())
+
 	if k.Name == "" && k.ID == "" {
 		return false, ErrExists
 	}
 
 	var count int
 
-	query := `
-SELECT 1 FROM tsg_keys
-WHERE (id = $1 OR name = $2) AND archived = false;
-`
 	// NOTE(justinwr): seriously...
 	keyID := "00000000-0000-0000-0000-000000000000"
 	if k.ID != "" {
 		keyID = k.ID
 	}
 
-	err := k.store.pool.QueryRowEx(ctx, query, nil,
+	queryErr := k.store.pool.QueryRowEx(ctx, existsKeyStmt, nil,
 		keyID,
 		k.Name,
 	).Scan(&count)
-	switch err {
+	switch queryErr {
 	case nil:
 		return true, nil
 	case pgx.ErrNoRows:
 		return false, nil
 	default:
-		return false, errors.Wrap(err, "failed to check key existence")
+		err = errors.Wrap(queryErr, "failed to check key existence")
+		logError(ctx, "failed to check key existence", k, queryErr)
+		return false, err
 	}
 }
@@ -0,0 +1,80 @@
+package keys
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// LocalAESProvider is a KMSProvider backed by an AES-GCM master key held
+// in process memory, as configured via config.NewDefault. It supports key
+// rotation by keeping retired master keys around long enough to unwrap
+// data keys they previously wrapped.
+type LocalAESProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewLocalAESProvider constructs a LocalAESProvider whose current master
+// key is version. previous, if non-nil, holds retired master keys by
+// version so data keys wrapped before a rotation can still be unwrapped.
+func NewLocalAESProvider(version string, masterKey []byte, previous map[string][]byte) (*LocalAESProvider, error) {
+	if len(masterKey) != 32 {
+		return nil, errors.New("master key must be 32 bytes for AES-256-GCM")
+	}
+
+	keys := make(map[string][]byte, len(previous)+1)
+	for v, k := range previous {
+		keys[v] = k
+	}
+	keys[version] = masterKey
+
+	return &LocalAESProvider{
+		current: version,
+		keys:    keys,
+	}, nil
+}
+
+// KeyVersion returns the version identifier of the current master key.
+func (p *LocalAESProvider) KeyVersion() string {
+	return p.current
+}
+
+// Wrap encrypts dataKey with the current master key.
+func (p *LocalAESProvider) Wrap(ctx context.Context, dataKey []byte) ([]byte, error) {
+	gcm, err := newGCM(p.keys[p.current])
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	return append(nonce, gcm.Seal(nil, nonce, dataKey, nil)...), nil
+}
+
+// Unwrap decrypts a data key that was wrapped under keyVersion, which may
+// be a retired master key still held by the provider.
+func (p *LocalAESProvider) Unwrap(ctx context.Context, keyVersion string, wrapped []byte) ([]byte, error) {
+	masterKey, ok := p.keys[keyVersion]
+	if !ok {
+		return nil, errors.Errorf("unknown master key version %q", keyVersion)
+	}
+
+	gcm, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+
+	nonce, ciphertext := wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
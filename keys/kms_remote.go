@@ -0,0 +1,74 @@
+package keys
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// ErrKMSNotConfigured is returned by remote KMSProvider stubs that have
+// not yet been wired up to their backing service.
+var ErrKMSNotConfigured = errors.New("remote KMS provider is not configured")
+
+// VaultKMSProvider holds the settings for HashiCorp Vault's transit
+// secrets engine: Address and Token identify the Vault server and a token
+// authorized to use the configured transit key. It does not contact Vault;
+// Wrap and Unwrap always return ErrKMSNotConfigured.
+type VaultKMSProvider struct {
+	Address string
+	Token   string
+	KeyName string
+}
+
+// KeyVersion returns the transit key name, since Vault tracks key
+// versions internally and returns them alongside ciphertext.
+func (p *VaultKMSProvider) KeyVersion() string {
+	return p.KeyName
+}
+
+func (p *VaultKMSProvider) Wrap(ctx context.Context, dataKey []byte) ([]byte, error) {
+	return nil, ErrKMSNotConfigured
+}
+
+func (p *VaultKMSProvider) Unwrap(ctx context.Context, keyVersion string, wrappedKey []byte) ([]byte, error) {
+	return nil, ErrKMSNotConfigured
+}
+
+// AWSKMSProvider holds the settings for an AWS KMS customer master key
+// identified by KeyID. It does not contact AWS KMS; Wrap and Unwrap always
+// return ErrKMSNotConfigured.
+type AWSKMSProvider struct {
+	KeyID  string
+	Region string
+}
+
+func (p *AWSKMSProvider) KeyVersion() string {
+	return p.KeyID
+}
+
+func (p *AWSKMSProvider) Wrap(ctx context.Context, dataKey []byte) ([]byte, error) {
+	return nil, ErrKMSNotConfigured
+}
+
+func (p *AWSKMSProvider) Unwrap(ctx context.Context, keyVersion string, wrappedKey []byte) ([]byte, error) {
+	return nil, ErrKMSNotConfigured
+}
+
+// GCPKMSProvider holds the settings for a GCP Cloud KMS key identified by
+// its full resource name. It does not contact Cloud KMS; Wrap and Unwrap
+// always return ErrKMSNotConfigured.
+type GCPKMSProvider struct {
+	KeyName string
+}
+
+func (p *GCPKMSProvider) KeyVersion() string {
+	return p.KeyName
+}
+
+func (p *GCPKMSProvider) Wrap(ctx context.Context, dataKey []byte) ([]byte, error) {
+	return nil, ErrKMSNotConfigured
+}
+
+func (p *GCPKMSProvider) Unwrap(ctx context.Context, keyVersion string, wrappedKey []byte) ([]byte, error) {
+	return nil, ErrKMSNotConfigured
+}
@@ -0,0 +1,32 @@
+package keys
+
+import (
+	"context"
+
+	"github.com/joyent/triton-service-groups/log"
+)
+
+// activeLogger is used to emit contextual logs on Insert/Save/Exists
+// failures.
+var activeLogger log.Logger
+
+// SetLogger installs the log.Logger used by the keys package. It is
+// typically called once during startup from config.NewDefault.
+func SetLogger(l log.Logger) {
+	activeLogger = l
+}
+
+// logError logs a failed key mutation with the fields useful for tracing
+// it back to a caller and a request.
+func logError(ctx context.Context, msg string, k *Key, err error) {
+	if activeLogger == nil {
+		return
+	}
+
+	activeLogger.With(ctx).Error(msg,
+		log.F("key_id", k.ID),
+		log.F("name", k.Name),
+		log.F("account_id", k.AccountID),
+		log.F("error", err.Error()),
+	)
+}
@@ -0,0 +1,54 @@
+package keys
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// migrateBatchSize bounds how many tsg_keys rows MigratePlaintextMaterial
+// loads per round trip, so a large table is paged through rather than
+// loaded into memory all at once.
+const migrateBatchSize = 500
+
+// zeroUUID sorts before every tsg_keys id, so it seeds the keyset
+// pagination in MigratePlaintextMaterial without a sentinel "first page"
+// branch.
+const zeroUUID = "00000000-0000-0000-0000-000000000000"
+
+// MigratePlaintextMaterial re-saves every tsg_keys row whose material is
+// still plaintext, encrypting it under the currently configured
+// KMSProvider. It is safe to run repeatedly: rows already encrypted are
+// skipped. Rows are paged through migrateBatchSize at a time using
+// keyset pagination on id, rather than a single unbounded SELECT.
+func MigratePlaintextMaterial(ctx context.Context, store *Store) (int, error) {
+	if activeKMS == nil {
+		return 0, errors.New("no KMSProvider configured")
+	}
+
+	var migrated int
+	lastID := zeroUUID
+
+	for {
+		page, err := store.findPendingMaterial(ctx, lastID, migrateBatchSize)
+		if err != nil {
+			return migrated, err
+		}
+		if len(page) == 0 {
+			return migrated, nil
+		}
+
+		for _, k := range page {
+			if !strings.HasPrefix(k.Material, materialCiphertextPrefix) {
+				if err := k.Save(ctx); err != nil {
+					return migrated, errors.Wrapf(err, "failed to re-save key %s", k.ID)
+				}
+
+				migrated++
+			}
+
+			lastID = k.ID
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package keys
+
+import (
+	"github.com/jackc/pgx"
+	"github.com/pkg/errors"
+)
+
+// Prepared statement names registered by PrepareStatements. Insert and
+// BulkInsert use insertKeyStmt directly so pgx resolves it from its
+// per-connection statement cache instead of re-parsing the SQL text on
+// every call.
+const (
+	insertKeyStmt     = "tsg_keys_insert"
+	updateKeyStmt     = "tsg_keys_update"
+	existsKeyStmt     = "tsg_keys_exists"
+	findByNameKeyStmt = "tsg_keys_find_by_name"
+)
+
+// PrepareStatements registers the prepared statements the keys package
+// relies on. It is installed as pgx.ConnPoolConfig.AfterConnect in
+// config.NewDefault, so every pooled connection has them ready before the
+// first query runs.
+func PrepareStatements(conn *pgx.Conn) error {
+	statements := []struct {
+		name string
+		sql  string
+	}{
+		{insertKeyStmt, `
+INSERT INTO tsg_keys (name, fingerprint, material, account_id, archived, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+RETURNING id, created_at, updated_at;
+`},
+		{updateKeyStmt, `
+UPDATE tsg_keys SET (name, fingerprint, material, archived, updated_at) = ($2, $3, $4, $5, $6)
+WHERE id = $1;
+`},
+		{existsKeyStmt, `
+SELECT 1 FROM tsg_keys
+WHERE (id = $1 OR name = $2) AND archived = false;
+`},
+		{findByNameKeyStmt, `
+SELECT id, name, fingerprint, material, account_id, archived, created_at, updated_at
+FROM tsg_keys
+WHERE name = $1 AND account_id = $2 AND archived = false;
+`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := conn.Prepare(stmt.name, stmt.sql); err != nil {
+			return errors.Wrapf(err, "failed to prepare statement %q", stmt.name)
+		}
+	}
+
+	return nil
+}
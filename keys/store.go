@@ -0,0 +1,269 @@
+package keys
+
+import (
+	"context"
+
+	"github.com/jackc/pgx"
+	"github.com/pkg/errors"
+)
+
+// Store wraps a pgx connection pool and provides persistence for Key
+// records backed by the tsg_keys table.
+type Store struct {
+	pool *pgx.ConnPool
+}
+
+// NewStore constructs a Store backed by the given connection pool.
+func NewStore(pool *pgx.ConnPool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+// FindByName looks up a non-archived key by name and account. Material is
+// decrypted before it is returned to the caller.
+func (s *Store) FindByName(ctx context.Context, name, accountID string) (*Key, error) {
+	key := New(s)
+
+	row := s.pool.QueryRowEx(ctx, findByNameKeyStmt, nil, name, accountID)
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Fingerprint,
+		&key.Material,
+		&key.AccountID,
+		&key.Archived,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find key by name")
+	}
+
+	if err := decryptMaterial(ctx, key); err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt key material")
+	}
+
+	return key, nil
+}
+
+// BulkInsert writes many keys to tsg_keys in a single network round trip
+// using a pgx.Batch, rather than one round trip per key. This matters for
+// account onboarding flows that provision dozens of keys at once. Each
+// key's Material is encrypted before it is queued, and every key's ID and
+// timestamps are populated from the batch results on success.
+func (s *Store) BulkInsert(ctx context.Context, keys []*Key) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	plaintexts := make([]string, len(keys))
+	var encrypted int
+	defer func() {
+		for i := 0; i < encrypted; i++ {
+			keys[i].Material = plaintexts[i]
+		}
+	}()
+
+	for i, k := range keys {
+		if k.AccountID == "" {
+			return ErrNoAccountID
+		}
+
+		plaintexts[i] = k.Material
+		if err := encryptMaterial(ctx, k); err != nil {
+			return errors.Wrap(err, "failed to encrypt key material")
+		}
+		encrypted = i + 1
+	}
+
+	batch := s.pool.BeginBatch()
+
+	for _, k := range keys {
+		batch.Queue(insertKeyStmt,
+			[]interface{}{k.Name, k.Fingerprint, k.Material, k.AccountID, k.Archived},
+			nil,
+			nil,
+		)
+	}
+
+	if err := batch.Send(ctx, nil); err != nil {
+		return errors.Wrap(err, "failed to send bulk insert batch")
+	}
+	defer batch.Close() // nolint: errcheck
+
+	for _, k := range keys {
+		row, err := batch.QueryRowResults()
+		if err != nil {
+			return errors.Wrap(err, "failed to read bulk insert result")
+		}
+
+		if err := row.Scan(&k.ID, &k.CreatedAt, &k.UpdatedAt); err != nil {
+			return errors.Wrap(err, "failed to scan bulk insert result")
+		}
+	}
+
+	for _, k := range keys {
+		auditEvent(ctx, AuditActionInsert, k, nil)
+	}
+
+	return nil
+}
+
+// FindByID looks up a key by id regardless of archived state. Material is
+// decrypted before it is returned to the caller.
+func (s *Store) FindByID(ctx context.Context, id string) (*Key, error) {
+	key := New(s)
+
+	err := s.pool.QueryRowEx(ctx, `
+SELECT id, name, fingerprint, material, account_id, archived, created_at, updated_at
+FROM tsg_keys
+WHERE id = $1;
+`, nil, id).Scan(
+		&key.ID,
+		&key.Name,
+		&key.Fingerprint,
+		&key.Material,
+		&key.AccountID,
+		&key.Archived,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find key by id")
+	}
+
+	if err := decryptMaterial(ctx, key); err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt key material")
+	}
+
+	return key, nil
+}
+
+// List returns up to limit non-archived keys for accountID, ordered by
+// creation time, starting after the given offset. Callers page through
+// the full result set by repeatedly increasing offset by the number of
+// rows returned until a page comes back short of limit.
+func (s *Store) List(ctx context.Context, accountID string, limit, offset int) ([]*Key, error) {
+	rows, err := s.pool.QueryEx(ctx, `
+SELECT id, name, fingerprint, material, account_id, archived, created_at, updated_at
+FROM tsg_keys
+WHERE account_id = $1 AND archived = false
+ORDER BY created_at
+LIMIT $2 OFFSET $3;
+`, nil, accountID, limit, offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list keys")
+	}
+	defer rows.Close()
+
+	var page []*Key
+	for rows.Next() {
+		k := New(s)
+		if err := rows.Scan(
+			&k.ID,
+			&k.Name,
+			&k.Fingerprint,
+			&k.Material,
+			&k.AccountID,
+			&k.Archived,
+			&k.CreatedAt,
+			&k.UpdatedAt,
+		); err != nil {
+			return nil, errors.Wrap(err, "failed to scan key")
+		}
+
+		if err := decryptMaterial(ctx, k); err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt key material")
+		}
+
+		page = append(page, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to iterate keys")
+	}
+
+	return page, nil
+}
+
+// RotateKey re-wraps the data key protecting id's Material under the
+// KMSProvider's current master key, without decrypting or re-encrypting
+// the ciphertext itself.
+func (s *Store) RotateKey(ctx context.Context, id string) error {
+	var material string
+
+	err := s.pool.QueryRowEx(ctx, `SELECT material FROM tsg_keys WHERE id = $1;`, nil, id).Scan(&material)
+	if err != nil {
+		return errors.Wrap(err, "failed to load key material")
+	}
+
+	rotated, err := RotateMaterialKey(ctx, material)
+	if err != nil {
+		return errors.Wrap(err, "failed to rotate key material")
+	}
+
+	_, err = s.pool.ExecEx(ctx, `UPDATE tsg_keys SET material = $2 WHERE id = $1;`, nil, id, rotated)
+
+	return errors.Wrap(err, "failed to persist rotated key material")
+}
+
+// findPendingMaterial returns up to limit tsg_keys rows with id greater
+// than afterID, ordered by id, for MigratePlaintextMaterial to page
+// through the table without loading it all into memory at once.
+func (s *Store) findPendingMaterial(ctx context.Context, afterID string, limit int) ([]*Key, error) {
+	rows, err := s.pool.QueryEx(ctx, `
+SELECT id, name, fingerprint, material, account_id, archived, created_at, updated_at
+FROM tsg_keys
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+`, nil, afterID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query keys")
+	}
+	defer rows.Close()
+
+	var page []*Key
+	for rows.Next() {
+		k := New(s)
+		if err := rows.Scan(
+			&k.ID,
+			&k.Name,
+			&k.Fingerprint,
+			&k.Material,
+			&k.AccountID,
+			&k.Archived,
+			&k.CreatedAt,
+			&k.UpdatedAt,
+		); err != nil {
+			return nil, errors.Wrap(err, "failed to scan key")
+		}
+
+		page = append(page, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to iterate keys")
+	}
+
+	return page, nil
+}
+
+// findAuditSnapshot captures the pre-mutation field values used as the
+// "before" side of an audit event. Lookup failures are swallowed: a
+// missing before-snapshot must never block the mutation it's describing.
+func (s *Store) findAuditSnapshot(ctx context.Context, id string) map[string]interface{} {
+	var name string
+	var archived bool
+
+	err := s.pool.QueryRowEx(ctx, `
+SELECT name, archived FROM tsg_keys WHERE id = $1;
+`, nil, id).Scan(&name, &archived)
+	if err != nil {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"name":     name,
+		"archived": archived,
+	}
+}
@@ -0,0 +1,83 @@
+// Package log provides a structured logging facade that packages can
+// depend on without committing to a particular logging library, so
+// operators can pick zerolog or log/slog output via config.NewDefault
+// without a recompile.
+package log
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Logger is implemented by every supported logging backend.
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+	Fatal(msg string, fields ...Field)
+
+	// With returns a Logger that attaches request-scoped fields found in
+	// ctx, such as a request id, to every subsequent log line.
+	With(ctx context.Context) Logger
+}
+
+// Field is a single structured logging key/value pair.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F constructs a Field.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Backend selects which logging library backs a Logger.
+type Backend string
+
+const (
+	BackendZerologConsole Backend = "zerolog-console"
+	BackendZerologJSON    Backend = "zerolog-json"
+	BackendSlog           Backend = "slog"
+)
+
+// ParseBackend parses the KeyAgentLogBackend viper value into a Backend,
+// defaulting to BackendZerologConsole for an empty string.
+func ParseBackend(s string) (Backend, error) {
+	switch Backend(s) {
+	case "":
+		return BackendZerologConsole, nil
+	case BackendZerologConsole, BackendZerologJSON, BackendSlog:
+		return Backend(s), nil
+	default:
+		return "", errors.Errorf("unsupported log backend %q", s)
+	}
+}
+
+// New constructs a Logger backed by backend. module identifies the
+// package or component doing the logging, and is attached to every line.
+func New(backend Backend, module string) Logger {
+	if backend == BackendSlog {
+		return newSlogLogger(module)
+	}
+
+	return newZerologLogger(backend, module)
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying id, so it is picked
+// up by Logger.With.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request id stashed by
+// ContextWithRequestID, or "" if none was set.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
@@ -0,0 +1,47 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// slogLogger implements Logger on top of the standard library's log/slog,
+// for operators who'd rather standardize on it than zerolog.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func newSlogLogger(module string) *slogLogger {
+	return &slogLogger{
+		logger: slog.New(slog.NewJSONHandler(os.Stderr, nil)).With("module", module),
+	}
+}
+
+func (l *slogLogger) Debug(msg string, fields ...Field) { l.log(slog.LevelDebug, msg, fields) }
+func (l *slogLogger) Info(msg string, fields ...Field)  { l.log(slog.LevelInfo, msg, fields) }
+func (l *slogLogger) Warn(msg string, fields ...Field)  { l.log(slog.LevelWarn, msg, fields) }
+func (l *slogLogger) Error(msg string, fields ...Field) { l.log(slog.LevelError, msg, fields) }
+
+func (l *slogLogger) Fatal(msg string, fields ...Field) {
+	l.log(slog.LevelError, msg, fields)
+	os.Exit(1)
+}
+
+func (l *slogLogger) With(ctx context.Context) Logger {
+	logger := l.logger
+	if id := RequestIDFromContext(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+
+	return &slogLogger{logger: logger}
+}
+
+func (l *slogLogger) log(level slog.Level, msg string, fields []Field) {
+	args := make([]interface{}, 0, len(fields)*2)
+	for _, f := range fields {
+		args = append(args, f.Key, f.Value)
+	}
+
+	l.logger.Log(context.Background(), level, msg, args...)
+}
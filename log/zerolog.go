@@ -0,0 +1,53 @@
+package log
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// zerologLogger implements Logger on top of zerolog, writing either
+// human-readable console output or newline-delimited JSON.
+type zerologLogger struct {
+	logger zerolog.Logger
+}
+
+func newZerologLogger(backend Backend, module string) *zerologLogger {
+	var writer io.Writer = os.Stderr
+	if backend == BackendZerologConsole {
+		writer = zerolog.ConsoleWriter{Out: os.Stderr}
+	}
+
+	return &zerologLogger{
+		logger: zerolog.New(writer).With().Timestamp().Str("module", module).Logger(),
+	}
+}
+
+func (l *zerologLogger) Debug(msg string, fields ...Field) { l.event(zerolog.DebugLevel, msg, fields) }
+func (l *zerologLogger) Info(msg string, fields ...Field)  { l.event(zerolog.InfoLevel, msg, fields) }
+func (l *zerologLogger) Warn(msg string, fields ...Field)  { l.event(zerolog.WarnLevel, msg, fields) }
+func (l *zerologLogger) Error(msg string, fields ...Field) { l.event(zerolog.ErrorLevel, msg, fields) }
+
+func (l *zerologLogger) Fatal(msg string, fields ...Field) {
+	l.event(zerolog.FatalLevel, msg, fields)
+	os.Exit(1)
+}
+
+func (l *zerologLogger) With(ctx context.Context) Logger {
+	logger := l.logger
+	if id := RequestIDFromContext(ctx); id != "" {
+		logger = logger.With().Str("request_id", id).Logger()
+	}
+
+	return &zerologLogger{logger: logger}
+}
+
+func (l *zerologLogger) event(level zerolog.Level, msg string, fields []Field) {
+	evt := l.logger.WithLevel(level)
+	for _, f := range fields {
+		evt = evt.Interface(f.Key, f.Value)
+	}
+	evt.Msg(msg)
+}
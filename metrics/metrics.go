@@ -0,0 +1,133 @@
+// Package metrics exports Prometheus counters, histograms, and gauges for
+// keys.Key operations and the pgx connection pool, so operators can alert
+// on key-write failures and size the pool instead of guessing.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// KeyOperationDuration tracks how long keys.Key operations take, by
+	// operation ("insert", "save", "exists") and result ("success",
+	// "error").
+	KeyOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "tsg",
+		Subsystem: "keys",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of keys.Key operations against tsg_keys.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "result"})
+
+	// KeyOperationErrors counts failed keys.Key operations, bucketed into
+	// a small set of error classes so alerts don't need a label per
+	// unique error message.
+	KeyOperationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tsg",
+		Subsystem: "keys",
+		Name:      "operation_errors_total",
+		Help:      "Count of failed keys.Key operations.",
+	}, []string{"operation", "error_class"})
+
+	// KeyOperationsByAccount counts keys.Key operations per account, so
+	// operators can spot an account whose key-write rate has spiked.
+	KeyOperationsByAccount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tsg",
+		Subsystem: "keys",
+		Name:      "account_operations_total",
+		Help:      "Count of keys.Key operations by account.",
+	}, []string{"operation", "account_id"})
+
+	// PoolInFlightConnections reports pgx pool connections currently
+	// acquired by a caller.
+	PoolInFlightConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tsg",
+		Subsystem: "pgx_pool",
+		Name:      "in_flight_connections",
+		Help:      "Number of pgx pool connections currently acquired.",
+	})
+
+	// PoolMaxConnections reports the pool's configured connection limit,
+	// so PoolInFlightConnections can be read as a percentage of capacity.
+	PoolMaxConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tsg",
+		Subsystem: "pgx_pool",
+		Name:      "max_connections",
+		Help:      "Configured maximum number of pgx pool connections.",
+	})
+
+	// PoolConnectionsOpened counts new physical connections pgx has
+	// opened to CockroachDB, observed from AfterConnect. A rate much
+	// higher than expected usually means MaxConnections is too small for
+	// the workload.
+	PoolConnectionsOpened = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "tsg",
+		Subsystem: "pgx_pool",
+		Name:      "connections_opened_total",
+		Help:      "Count of new pgx pool connections opened.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		KeyOperationDuration,
+		KeyOperationErrors,
+		KeyOperationsByAccount,
+		PoolInFlightConnections,
+		PoolMaxConnections,
+		PoolConnectionsOpened,
+	)
+}
+
+// ObserveKeyOperation records the outcome of a keys.Key operation for
+// operation on behalf of accountID. Call it with the error returned from
+// the operation, or nil on success.
+func ObserveKeyOperation(operation, accountID string, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+		KeyOperationErrors.WithLabelValues(operation, errorClass(err)).Inc()
+	}
+
+	KeyOperationDuration.WithLabelValues(operation, result).Observe(duration.Seconds())
+	KeyOperationsByAccount.WithLabelValues(operation, accountID).Inc()
+}
+
+// errorClass buckets err into a small, stable label.
+func errorClass(err error) string {
+	switch {
+	case err == nil:
+		return "none"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "other"
+	}
+}
+
+// PollPoolStats samples pool.Stat() every interval and exports it as
+// gauges until ctx is done.
+func PollPoolStats(ctx context.Context, pool *pgx.ConnPool, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				stat := pool.Stat()
+				PoolInFlightConnections.Set(float64(stat.CurrentConnections - stat.AvailableConnections))
+				PoolMaxConnections.Set(float64(stat.MaxConnections))
+			}
+		}
+	}()
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewHandler returns the /metrics handler, plus /debug/pprof handlers
+// when pprofEnabled, on a dedicated mux so they aren't reachable through
+// the public HTTPServer listener.
+func NewHandler(pprofEnabled bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	if pprofEnabled {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	return mux
+}
+
+// ListenAndServe serves NewHandler on bind:port until ctx is done.
+func ListenAndServe(ctx context.Context, bind string, port uint16, pprofEnabled bool) error {
+	srv := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", bind, port),
+		Handler: NewHandler(pprofEnabled),
+	}
+
+	go func() {
+		<-ctx.Done()
+		srv.Close() // nolint: errcheck
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}